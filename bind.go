@@ -0,0 +1,467 @@
+package gml
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ovila98/ers"
+)
+
+// NodeMarshal converts v, a struct or pointer to struct, into a *Node tree
+// using the same struct tag conventions as encoding/xml: `xml:"name"`,
+// `xml:"name,attr"`, `xml:",chardata"`, `xml:",comment"`, `xml:",any"`, and
+// path tags such as `xml:"a>b>c"`. Slices produce repeated children,
+// pointers are optional, time.Time is encoded as RFC3339, and []byte is
+// base64-encoded.
+func NodeMarshal(v interface{}) (*Node, error) {
+	val := reflect.ValueOf(v)
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return nil, fmt.Errorf("gml: NodeMarshal called with a nil pointer")
+		}
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("gml: NodeMarshal requires a struct or pointer to struct, got %s", val.Kind())
+	}
+	node := &Node{Tag: rootTagName(val.Type())}
+	if err := marshalStructInto(node, val); err != nil {
+		return nil, ers.Trace(err)
+	}
+	return node, nil
+}
+
+// NodeUnmarshal populates v, a pointer to struct, from n using the same
+// struct tag conventions as NodeMarshal.
+func NodeUnmarshal(n *Node, v interface{}) error {
+	if n == nil {
+		return fmt.Errorf("gml: NodeUnmarshal called with a nil node")
+	}
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Ptr || val.IsNil() {
+		return fmt.Errorf("gml: NodeUnmarshal requires a non-nil pointer to struct")
+	}
+	val = val.Elem()
+	if val.Kind() != reflect.Struct {
+		return fmt.Errorf("gml: NodeUnmarshal requires a pointer to struct, got pointer to %s", val.Kind())
+	}
+	return unmarshalStructFrom(n, val)
+}
+
+// bindTag is a parsed `xml:"..."` struct tag.
+type bindTag struct {
+	pathParts []string
+	attr      bool
+	chardata  bool
+	comment   bool
+	any       bool
+	omitempty bool
+}
+
+func parseBindTag(tag, fieldName string) bindTag {
+	parts := strings.Split(tag, ",")
+	name := parts[0]
+	bt := bindTag{}
+	for _, opt := range parts[1:] {
+		switch opt {
+		case "attr":
+			bt.attr = true
+		case "chardata":
+			bt.chardata = true
+		case "comment":
+			bt.comment = true
+		case "any":
+			bt.any = true
+		case "omitempty":
+			bt.omitempty = true
+		}
+	}
+	if name == "" {
+		name = fieldName
+	}
+	bt.pathParts = strings.Split(name, ">")
+	return bt
+}
+
+func rootTagName(typ reflect.Type) string {
+	name := typ.Name()
+	if field, ok := typ.FieldByName("XMLName"); ok && field.Type == reflect.TypeOf(xml.Name{}) {
+		if tag := field.Tag.Get("xml"); tag != "" {
+			if local := strings.SplitN(tag, ",", 2)[0]; local != "" {
+				name = local
+			}
+		}
+	}
+	return name
+}
+
+func marshalStructInto(node *Node, val reflect.Value) error {
+	typ := val.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" || field.Name == "XMLName" {
+			continue
+		}
+		tagStr := field.Tag.Get("xml")
+		if tagStr == "-" {
+			continue
+		}
+		bt := parseBindTag(tagStr, field.Name)
+		fv := val.Field(i)
+		if bt.omitempty && isEmptyBindValue(fv) {
+			continue
+		}
+		switch {
+		case bt.attr:
+			s, err := marshalScalarString(fv)
+			if err != nil {
+				return ers.Trace(err)
+			}
+			node.SetAttribute(bt.pathParts[len(bt.pathParts)-1], s)
+		case bt.chardata:
+			s, err := marshalScalarString(fv)
+			if err != nil {
+				return ers.Trace(err)
+			}
+			node.InnerText = s
+		case bt.comment:
+			s, err := marshalScalarString(fv)
+			if err != nil {
+				return ers.Trace(err)
+			}
+			node.AppendChild(&Node{Type: CommentNode, InnerText: s})
+		case bt.any:
+			if err := marshalAnyField(node, fv); err != nil {
+				return ers.Trace(err)
+			}
+		default:
+			if err := marshalElementField(node, bt.pathParts, fv); err != nil {
+				return ers.Trace(err)
+			}
+		}
+	}
+	return nil
+}
+
+func marshalAnyField(node *Node, fv reflect.Value) error {
+	if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+		for i := 0; i < fv.Len(); i++ {
+			child, err := marshalAnyValue(fv.Index(i))
+			if err != nil {
+				return ers.Trace(err)
+			}
+			if child != nil {
+				node.AppendChild(child)
+			}
+		}
+		return nil
+	}
+	child, err := marshalAnyValue(fv)
+	if err != nil {
+		return ers.Trace(err)
+	}
+	if child != nil {
+		node.AppendChild(child)
+	}
+	return nil
+}
+
+func marshalAnyValue(fv reflect.Value) (*Node, error) {
+	for fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			return nil, nil
+		}
+		fv = fv.Elem()
+	}
+	if fv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("gml: an \",any\" field must hold a struct, got %s", fv.Kind())
+	}
+	child := &Node{Tag: rootTagName(fv.Type())}
+	if err := marshalStructInto(child, fv); err != nil {
+		return nil, ers.Trace(err)
+	}
+	return child, nil
+}
+
+// marshalElementField appends the element(s) produced by fv under parent,
+// creating (and reusing, for fields sharing a prefix) any intermediate
+// containers named by path.
+func marshalElementField(parent *Node, path []string, fv reflect.Value) error {
+	container := parent
+	if len(path) > 1 {
+		container = parent.EnsurePath(path[:len(path)-1]...)
+	}
+	leaf := path[len(path)-1]
+
+	switch {
+	case fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.Uint8:
+		container.AppendChild(&Node{Tag: leaf, InnerText: base64.StdEncoding.EncodeToString(fv.Bytes())})
+	case fv.Kind() == reflect.Slice:
+		for i := 0; i < fv.Len(); i++ {
+			if err := marshalElementField(container, []string{leaf}, fv.Index(i)); err != nil {
+				return ers.Trace(err)
+			}
+		}
+	case fv.Kind() == reflect.Ptr:
+		if fv.IsNil() {
+			return nil
+		}
+		return marshalElementField(container, []string{leaf}, fv.Elem())
+	case fv.Kind() == reflect.Struct && fv.Type() != reflect.TypeOf(time.Time{}):
+		child := &Node{Tag: leaf}
+		if err := marshalStructInto(child, fv); err != nil {
+			return ers.Trace(err)
+		}
+		container.AppendChild(child)
+	default:
+		s, err := marshalScalarString(fv)
+		if err != nil {
+			return ers.Trace(err)
+		}
+		container.AppendChild(&Node{Tag: leaf, InnerText: s})
+	}
+	return nil
+}
+
+func marshalScalarString(fv reflect.Value) (string, error) {
+	switch fv.Kind() {
+	case reflect.String:
+		return fv.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(fv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(fv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(fv.Float(), 'f', -1, 64), nil
+	case reflect.Bool:
+		return strconv.FormatBool(fv.Bool()), nil
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() == reflect.Uint8 {
+			return base64.StdEncoding.EncodeToString(fv.Bytes()), nil
+		}
+	case reflect.Struct:
+		if t, ok := fv.Interface().(time.Time); ok {
+			return t.Format(time.RFC3339), nil
+		}
+	case reflect.Ptr:
+		if fv.IsNil() {
+			return "", nil
+		}
+		return marshalScalarString(fv.Elem())
+	}
+	return "", fmt.Errorf("gml: unsupported field type %s for xml encoding", fv.Type())
+}
+
+func isEmptyBindValue(fv reflect.Value) bool {
+	switch fv.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return fv.Len() == 0
+	case reflect.Bool:
+		return !fv.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return fv.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return fv.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return fv.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return fv.IsNil()
+	default:
+		return false
+	}
+}
+
+func unmarshalStructFrom(n *Node, val reflect.Value) error {
+	typ := val.Type()
+	tags := make([]bindTag, typ.NumField())
+	claimed := make(map[string]bool)
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" || field.Name == "XMLName" {
+			continue
+		}
+		tagStr := field.Tag.Get("xml")
+		if tagStr == "-" {
+			continue
+		}
+		bt := parseBindTag(tagStr, field.Name)
+		tags[i] = bt
+		if !bt.any && !bt.attr && !bt.chardata && !bt.comment {
+			claimed[bt.pathParts[0]] = true
+		}
+	}
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		fv := val.Field(i)
+		if field.Name == "XMLName" {
+			if field.Type == reflect.TypeOf(xml.Name{}) {
+				fv.Set(reflect.ValueOf(xml.Name{Local: n.Tag}))
+			}
+			continue
+		}
+		if field.Tag.Get("xml") == "-" {
+			continue
+		}
+		bt := tags[i]
+		switch {
+		case bt.attr:
+			raw, ok := n.Attributes[bt.pathParts[len(bt.pathParts)-1]]
+			if !ok {
+				continue
+			}
+			if err := setScalarFromString(fv, raw); err != nil {
+				return ers.Trace(err)
+			}
+		case bt.chardata:
+			if err := setScalarFromString(fv, n.InnerText); err != nil {
+				return ers.Trace(err)
+			}
+		case bt.comment:
+			for _, c := range n.Children {
+				if c.Type == CommentNode {
+					if err := setScalarFromString(fv, c.InnerText); err != nil {
+						return ers.Trace(err)
+					}
+					break
+				}
+			}
+		case bt.any:
+			var leftover []*Node
+			for _, c := range n.Children {
+				if !claimed[c.Tag] {
+					leftover = append(leftover, c)
+				}
+			}
+			if err := setFieldFromNodes(fv, leftover); err != nil {
+				return ers.Trace(err)
+			}
+		default:
+			container := n
+			for _, seg := range bt.pathParts[:len(bt.pathParts)-1] {
+				container = container.findImmediateChild(seg)
+				if container == nil {
+					break
+				}
+			}
+			if container == nil {
+				continue
+			}
+			matches := container.FindAllChildren(bt.pathParts[len(bt.pathParts)-1])
+			if err := setFieldFromNodes(fv, matches); err != nil {
+				return ers.Trace(err)
+			}
+		}
+	}
+	return nil
+}
+
+// setFieldFromNodes populates fv, a slice field or a singular field, from
+// the matched nodes. For slice fields every match becomes an element; for
+// singular fields only the first match is used.
+func setFieldFromNodes(fv reflect.Value, nodes []*Node) error {
+	if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+		slice := reflect.MakeSlice(fv.Type(), 0, len(nodes))
+		for _, node := range nodes {
+			elem := reflect.New(fv.Type().Elem()).Elem()
+			if err := setFieldFromNode(elem, node); err != nil {
+				return ers.Trace(err)
+			}
+			slice = reflect.Append(slice, elem)
+		}
+		fv.Set(slice)
+		return nil
+	}
+	if len(nodes) == 0 {
+		return nil
+	}
+	return setFieldFromNode(fv, nodes[0])
+}
+
+func setFieldFromNode(fv reflect.Value, node *Node) error {
+	switch {
+	case fv.Kind() == reflect.Ptr:
+		if fv.IsNil() {
+			fv.Set(reflect.New(fv.Type().Elem()))
+		}
+		return setFieldFromNode(fv.Elem(), node)
+	case fv.Kind() == reflect.Struct && fv.Type() != reflect.TypeOf(time.Time{}):
+		return unmarshalStructFrom(node, fv)
+	default:
+		return setScalarFromString(fv, node.InnerText)
+	}
+}
+
+func setScalarFromString(fv reflect.Value, s string) error {
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return ers.Trace(err)
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return ers.Trace(err)
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return ers.Trace(err)
+		}
+		fv.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return ers.Trace(err)
+		}
+		fv.SetBool(b)
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("gml: unsupported slice field type %s for xml decoding", fv.Type())
+		}
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return ers.Trace(err)
+		}
+		fv.SetBytes(b)
+	case reflect.Struct:
+		if fv.Type() != reflect.TypeOf(time.Time{}) {
+			return fmt.Errorf("gml: unsupported struct field type %s for xml decoding", fv.Type())
+		}
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return ers.Trace(err)
+		}
+		fv.Set(reflect.ValueOf(t))
+	case reflect.Ptr:
+		if fv.IsNil() {
+			fv.Set(reflect.New(fv.Type().Elem()))
+		}
+		return setScalarFromString(fv.Elem(), s)
+	default:
+		return fmt.Errorf("gml: unsupported field kind %s for xml decoding", fv.Kind())
+	}
+	return nil
+}
+
+func (n *Node) findImmediateChild(tag string) *Node {
+	for _, child := range n.Children {
+		if child.Tag == tag {
+			return child
+		}
+	}
+	return nil
+}
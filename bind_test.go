@@ -0,0 +1,94 @@
+package gml
+
+import (
+	"testing"
+	"time"
+)
+
+type bindBook struct {
+	Title     string `xml:"title"`
+	Author    string `xml:"author"`
+	Published int    `xml:"published"`
+}
+
+type bindSection struct {
+	Name  string     `xml:"name,attr"`
+	Books []bindBook `xml:"book"`
+}
+
+type bindLibrary struct {
+	Name      string        `xml:"name,attr"`
+	Rating    float64       `xml:"rating,attr"`
+	Opened    time.Time     `xml:"opened,attr"`
+	Logo      []byte        `xml:"logo"`
+	Sections  []bindSection `xml:"section"`
+	Note      string        `xml:"meta>note"`
+	Curator   *string       `xml:"curator,omitempty"`
+}
+
+// TestNodeMarshalUnmarshalRoundTrip tests that NodeMarshal and
+// NodeUnmarshal round-trip a struct through a *Node tree.
+func TestNodeMarshalUnmarshalRoundTrip(t *testing.T) {
+	opened, err := time.Parse(time.RFC3339, "2010-05-01T00:00:00Z")
+	if err != nil {
+		t.Fatalf("Error parsing fixture time: %v", err)
+	}
+	library := bindLibrary{
+		Name:   "City Library",
+		Rating: 4.3,
+		Opened: opened,
+		Logo:   []byte("logo-bytes"),
+		Sections: []bindSection{
+			{
+				Name: "Fiction",
+				Books: []bindBook{
+					{Title: "1984", Author: "George Orwell", Published: 1949},
+				},
+			},
+		},
+		Note: "staff pick",
+	}
+
+	node, err := NodeMarshal(&library)
+	if err != nil {
+		t.Fatalf("Error marshaling library: %v", err)
+	}
+	if node.Tag != "bindLibrary" {
+		t.Fatalf("Expected root tag 'bindLibrary', got '%s'", node.Tag)
+	}
+	if node.GetAttribute("name") != "City Library" {
+		t.Errorf("Expected name attribute 'City Library', got '%s'", node.GetAttribute("name"))
+	}
+	if node.GetAttribute("rating") != "4.3" {
+		t.Errorf("Expected rating attribute '4.3', got '%s'", node.GetAttribute("rating"))
+	}
+	if !node.CheckPath("meta", "note") {
+		t.Fatalf("Expected path 'meta/note' to exist from a '>' path tag")
+	}
+	if node.FindChild("curator") != nil {
+		t.Errorf("Expected omitempty to drop the nil curator field")
+	}
+
+	var decoded bindLibrary
+	if err := NodeUnmarshal(node, &decoded); err != nil {
+		t.Fatalf("Error unmarshaling library: %v", err)
+	}
+	if decoded.Name != library.Name || decoded.Rating != library.Rating {
+		t.Errorf("Expected decoded library to match original, got %+v", decoded)
+	}
+	if !decoded.Opened.Equal(library.Opened) {
+		t.Errorf("Expected decoded Opened %v to equal %v", decoded.Opened, library.Opened)
+	}
+	if string(decoded.Logo) != string(library.Logo) {
+		t.Errorf("Expected decoded Logo %q, got %q", library.Logo, decoded.Logo)
+	}
+	if len(decoded.Sections) != 1 || len(decoded.Sections[0].Books) != 1 {
+		t.Fatalf("Expected one section with one book, got %+v", decoded.Sections)
+	}
+	if decoded.Sections[0].Books[0].Title != "1984" || decoded.Sections[0].Books[0].Published != 1949 {
+		t.Errorf("Expected decoded book '1984' (1949), got %+v", decoded.Sections[0].Books[0])
+	}
+	if decoded.Note != "staff pick" {
+		t.Errorf("Expected decoded note 'staff pick', got '%s'", decoded.Note)
+	}
+}
@@ -0,0 +1,220 @@
+package gml
+
+// IndexInParent returns n's index within its parent's Children slice, or
+// -1 if n has no parent or is not actually present there.
+func (n *Node) IndexInParent() int {
+	if n == nil || n.Parent == nil {
+		return -1
+	}
+	for i, sibling := range n.Parent.Children {
+		if sibling == n {
+			return i
+		}
+	}
+	return -1
+}
+
+// NextSibling returns the node immediately after n in its parent's
+// Children, or nil if there is none.
+func (n *Node) NextSibling() *Node {
+	i := n.IndexInParent()
+	if i == -1 || i+1 >= len(n.Parent.Children) {
+		return nil
+	}
+	return n.Parent.Children[i+1]
+}
+
+// PrevSibling returns the node immediately before n in its parent's
+// Children, or nil if there is none.
+func (n *Node) PrevSibling() *Node {
+	i := n.IndexInParent()
+	if i <= 0 {
+		return nil
+	}
+	return n.Parent.Children[i-1]
+}
+
+// FirstChild returns n's first child, or nil if n has none.
+func (n *Node) FirstChild() *Node {
+	if len(n.Children) == 0 {
+		return nil
+	}
+	return n.Children[0]
+}
+
+// LastChild returns n's last child, or nil if n has none.
+func (n *Node) LastChild() *Node {
+	if len(n.Children) == 0 {
+		return nil
+	}
+	return n.Children[len(n.Children)-1]
+}
+
+// InsertBefore inserts newChild into n's Children immediately before ref,
+// or at the end if ref is nil or not a child of n, and returns newChild.
+func (n *Node) InsertBefore(newChild, ref *Node) *Node {
+	newChild.Parent = n
+	idx := -1
+	if ref != nil {
+		idx = ref.IndexInParent()
+	}
+	if idx == -1 || ref.Parent != n {
+		n.Children = append(n.Children, newChild)
+		return newChild
+	}
+	n.Children = append(n.Children, nil)
+	copy(n.Children[idx+1:], n.Children[idx:])
+	n.Children[idx] = newChild
+	return newChild
+}
+
+// InsertAfter inserts newChild into n's Children immediately after ref,
+// or at the end if ref is nil or not a child of n, and returns newChild.
+func (n *Node) InsertAfter(newChild, ref *Node) *Node {
+	newChild.Parent = n
+	idx := -1
+	if ref != nil {
+		idx = ref.IndexInParent()
+	}
+	if idx == -1 || ref.Parent != n || idx+1 >= len(n.Children) {
+		n.Children = append(n.Children, newChild)
+		return newChild
+	}
+	insertAt := idx + 1
+	n.Children = append(n.Children, nil)
+	copy(n.Children[insertAt+1:], n.Children[insertAt:])
+	n.Children[insertAt] = newChild
+	return newChild
+}
+
+// ReplaceChild replaces old with newNode in n's Children and returns old,
+// or nil if old is nil or not a child of n.
+func (n *Node) ReplaceChild(newNode, old *Node) *Node {
+	idx := old.IndexInParent()
+	if idx == -1 || old.Parent != n {
+		return nil
+	}
+	newNode.Parent = n
+	n.Children[idx] = newNode
+	old.Parent = nil
+	return old
+}
+
+// RemoveChild removes child from n's Children, if child is non-nil and
+// present.
+func (n *Node) RemoveChild(child *Node) {
+	idx := child.IndexInParent()
+	if idx == -1 || child.Parent != n {
+		return
+	}
+	n.Children = append(n.Children[:idx], n.Children[idx+1:]...)
+	child.Parent = nil
+}
+
+// Detach removes n from its parent's Children, if it has one.
+func (n *Node) Detach() {
+	if n == nil || n.Parent == nil {
+		return
+	}
+	n.Parent.RemoveChild(n)
+}
+
+// Clone returns a deep copy of n, with its own Attributes map and
+// recursively cloned Children whose Parent fields point into the clone.
+// The clone's Parent is nil; attach it with AppendChild or InsertBefore
+// if needed.
+func (n *Node) Clone() *Node {
+	if n == nil {
+		return nil
+	}
+	clone := &Node{
+		Tag:       n.Tag,
+		Type:      n.Type,
+		InnerText: n.InnerText,
+		Namespace: n.Namespace,
+		Prefix:    n.Prefix,
+	}
+	if n.Attributes != nil {
+		clone.Attributes = make(map[string]string, len(n.Attributes))
+		for k, v := range n.Attributes {
+			clone.Attributes[k] = v
+		}
+	}
+	if n.NamespaceDecls != nil {
+		clone.NamespaceDecls = make(map[string]string, len(n.NamespaceDecls))
+		for k, v := range n.NamespaceDecls {
+			clone.NamespaceDecls[k] = v
+		}
+	}
+	if n.attributeNS != nil {
+		clone.attributeNS = make(map[string]string, len(n.attributeNS))
+		for k, v := range n.attributeNS {
+			clone.attributeNS[k] = v
+		}
+	}
+	if n.attributePrefixes != nil {
+		clone.attributePrefixes = make(map[string]string, len(n.attributePrefixes))
+		for k, v := range n.attributePrefixes {
+			clone.attributePrefixes[k] = v
+		}
+	}
+	for _, child := range n.Children {
+		clone.AppendChild(child.Clone())
+	}
+	return clone
+}
+
+// Walk traverses the tree rooted at n in pre-order, calling visit on each
+// node. If visit returns false, n's children are skipped, but traversal
+// continues with n's siblings.
+func (n *Node) Walk(visit func(*Node) bool) {
+	if n == nil {
+		return
+	}
+	if !visit(n) {
+		return
+	}
+	for _, child := range n.Children {
+		child.Walk(visit)
+	}
+}
+
+// WalkFiltered traverses the entire tree rooted at n in pre-order, calling
+// visit on every node for which pred returns true.
+func (n *Node) WalkFiltered(pred func(*Node) bool, visit func(*Node)) {
+	if n == nil {
+		return
+	}
+	if pred(n) {
+		visit(n)
+	}
+	for _, child := range n.Children {
+		child.WalkFiltered(pred, visit)
+	}
+}
+
+// FindAllChildren returns every immediate child of n with the given tag,
+// in document order.
+func (n *Node) FindAllChildren(tag string) []*Node {
+	var matches []*Node
+	for _, child := range n.Children {
+		if child.Tag == tag {
+			matches = append(matches, child)
+		}
+	}
+	return matches
+}
+
+// FindAll recursively searches the tree rooted at n and returns every
+// node with the given tag, in document order. Unlike FindChild, it does
+// not stop at the first match.
+func (n *Node) FindAll(tag string) []*Node {
+	var matches []*Node
+	if n.Tag == tag {
+		matches = append(matches, n)
+	}
+	for _, child := range n.Children {
+		matches = append(matches, child.FindAll(tag)...)
+	}
+	return matches
+}
@@ -0,0 +1,208 @@
+package gml
+
+import (
+	"strings"
+	"testing"
+)
+
+func newDomTree() *Node {
+	root := &Node{Tag: "root"}
+	root.ChainAppendChildren(
+		&Node{Tag: "a"},
+		&Node{Tag: "b"},
+		&Node{Tag: "c"},
+	)
+	return root
+}
+
+// TestSiblingNavigation tests NextSibling, PrevSibling, FirstChild,
+// LastChild, and IndexInParent.
+func TestSiblingNavigation(t *testing.T) {
+	root := newDomTree()
+	a, b, c := root.Children[0], root.Children[1], root.Children[2]
+
+	if root.FirstChild() != a || root.LastChild() != c {
+		t.Fatalf("Expected FirstChild=a and LastChild=c")
+	}
+	if a.NextSibling() != b || b.NextSibling() != c || c.NextSibling() != nil {
+		t.Fatalf("Expected NextSibling chain a->b->c->nil")
+	}
+	if c.PrevSibling() != b || b.PrevSibling() != a || a.PrevSibling() != nil {
+		t.Fatalf("Expected PrevSibling chain c->b->a->nil")
+	}
+	if b.IndexInParent() != 1 || root.IndexInParent() != -1 {
+		t.Fatalf("Expected b.IndexInParent()==1 and root.IndexInParent()==-1")
+	}
+}
+
+// TestInsertBeforeAfter tests InsertBefore and InsertAfter.
+func TestInsertBeforeAfter(t *testing.T) {
+	root := newDomTree()
+	b := root.Children[1]
+
+	x := &Node{Tag: "x"}
+	root.InsertBefore(x, b)
+	if len(root.Children) != 4 || root.Children[1] != x || root.Children[2] != b {
+		t.Fatalf("Expected InsertBefore to place x right before b, got %v", tagsOf(root.Children))
+	}
+
+	y := &Node{Tag: "y"}
+	root.InsertAfter(y, b)
+	if len(root.Children) != 5 || root.Children[3] != y {
+		t.Fatalf("Expected InsertAfter to place y right after b, got %v", tagsOf(root.Children))
+	}
+
+	tail := &Node{Tag: "tail"}
+	root.InsertAfter(tail, &Node{Tag: "not-a-child"})
+	if root.LastChild() != tail {
+		t.Fatalf("Expected InsertAfter with an unknown ref to append at the end")
+	}
+
+	other := newDomTree()
+	foreignRef := other.Children[1]
+
+	before := &Node{Tag: "before"}
+	root.InsertBefore(before, foreignRef)
+	if root.LastChild() != before {
+		t.Fatalf("Expected InsertBefore with a ref belonging to another parent to append at the end")
+	}
+
+	after := &Node{Tag: "after"}
+	root.InsertAfter(after, foreignRef)
+	if root.LastChild() != after {
+		t.Fatalf("Expected InsertAfter with a ref belonging to another parent to append at the end")
+	}
+}
+
+// TestReplaceRemoveDetach tests ReplaceChild, RemoveChild, and Detach.
+func TestReplaceRemoveDetach(t *testing.T) {
+	root := newDomTree()
+	a, b, c := root.Children[0], root.Children[1], root.Children[2]
+
+	replacement := &Node{Tag: "replacement"}
+	old := root.ReplaceChild(replacement, b)
+	if old != b || root.Children[1] != replacement || replacement.Parent != root {
+		t.Fatalf("Expected ReplaceChild to swap in replacement for b")
+	}
+
+	root.RemoveChild(a)
+	if len(root.Children) != 2 || a.Parent != nil {
+		t.Fatalf("Expected RemoveChild to detach a, got %v", tagsOf(root.Children))
+	}
+
+	c.Detach()
+	if len(root.Children) != 1 || c.Parent != nil {
+		t.Fatalf("Expected Detach to remove c from root, got %v", tagsOf(root.Children))
+	}
+}
+
+// TestReplaceRemoveDetachNil tests that ReplaceChild, RemoveChild, and
+// Detach tolerate a nil node instead of panicking, since FindChild commonly
+// returns nil for a tag that isn't present.
+func TestReplaceRemoveDetachNil(t *testing.T) {
+	root := newDomTree()
+
+	if old := root.ReplaceChild(&Node{Tag: "x"}, root.FindChild("missing")); old != nil {
+		t.Fatalf("Expected ReplaceChild with a nil old to return nil, got %v", old)
+	}
+
+	root.RemoveChild(root.FindChild("missing"))
+	if len(root.Children) != 3 {
+		t.Fatalf("Expected RemoveChild with a nil child to be a no-op, got %v", tagsOf(root.Children))
+	}
+
+	root.FindChild("missing").Detach()
+	if len(root.Children) != 3 {
+		t.Fatalf("Expected Detach on a nil node to be a no-op, got %v", tagsOf(root.Children))
+	}
+}
+
+// TestClone tests that Clone produces an independent deep copy.
+func TestClone(t *testing.T) {
+	root := newDomTree()
+	root.SetAttribute("id", "1")
+	clone := root.Clone()
+
+	if clone == root || clone.Tag != root.Tag || len(clone.Children) != len(root.Children) {
+		t.Fatalf("Expected Clone to produce a structurally equal but distinct tree")
+	}
+	clone.SetAttribute("id", "2")
+	if root.GetAttribute("id") != "1" {
+		t.Errorf("Expected mutating the clone's attributes not to affect the original")
+	}
+	clone.Children[0].Tag = "changed"
+	if root.Children[0].Tag != "a" {
+		t.Errorf("Expected mutating the clone's children not to affect the original")
+	}
+	if clone.Children[0].Parent != clone {
+		t.Errorf("Expected cloned children to point back to the clone")
+	}
+}
+
+// TestCloneAttributePrefix tests that Clone preserves a namespace-prefixed
+// attribute's prefix.
+func TestCloneAttributePrefix(t *testing.T) {
+	root, err := Decode(strings.NewReader(`<root xmlns:dc="ns"><item dc:creator="me"></item></root>`), DecoderOptions{})
+	if err != nil {
+		t.Fatalf("Error decoding: %v", err)
+	}
+
+	clone := root.FindChild("item").Clone()
+	output := clone.String()
+	if !strings.Contains(output, `dc:creator="me"`) {
+		t.Fatalf("Expected Clone to preserve the dc: prefix on creator, got:\n%s", output)
+	}
+}
+
+// TestWalk tests that Walk visits nodes in pre-order and honors the
+// skip-subtree return value.
+func TestWalk(t *testing.T) {
+	root := newDomTree()
+	root.Children[1].AppendChild(&Node{Tag: "grandchild"})
+
+	var visited []string
+	root.Walk(func(n *Node) bool {
+		visited = append(visited, n.Tag)
+		return n.Tag != "b"
+	})
+	if len(visited) != 4 || visited[len(visited)-1] != "c" {
+		t.Fatalf("Expected Walk to skip b's subtree but still visit c, got %v", visited)
+	}
+}
+
+// TestWalkFiltered tests that WalkFiltered only calls visit on matching
+// nodes while still traversing the whole tree.
+func TestWalkFiltered(t *testing.T) {
+	root := newDomTree()
+	root.Children[1].AppendChild(&Node{Tag: "a"})
+
+	var count int
+	root.WalkFiltered(func(n *Node) bool { return n.Tag == "a" }, func(n *Node) {
+		count++
+	})
+	if count != 2 {
+		t.Fatalf("Expected WalkFiltered to find both 'a' nodes, got %d", count)
+	}
+}
+
+// TestFindAllChildrenAndFindAll tests the immediate-children and
+// recursive find-all helpers.
+func TestFindAllChildrenAndFindAll(t *testing.T) {
+	root := newDomTree()
+	root.Children[1].AppendChild(&Node{Tag: "a"})
+
+	if len(root.FindAllChildren("a")) != 1 {
+		t.Errorf("Expected FindAllChildren to only match immediate children")
+	}
+	if len(root.FindAll("a")) != 2 {
+		t.Errorf("Expected FindAll to match every descendant 'a' node")
+	}
+}
+
+func tagsOf(nodes []*Node) []string {
+	tags := make([]string, len(nodes))
+	for i, n := range nodes {
+		tags[i] = n.Tag
+	}
+	return tags
+}
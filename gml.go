@@ -12,24 +12,86 @@ import (
 
 // Node represents a node in an XML document.
 type Node struct {
-	// Tag is the name of the XML tag.
+	// Tag is the name of the XML tag. Unused on non-element nodes, except
+	// ProcInstNode, where it holds the processing instruction target.
 	Tag string
 	// Parent is a pointer to the parent Node.
 	Parent *Node
+	// Type identifies what kind of node this is. The zero value,
+	// ElementNode, covers every node built before Type existed, so
+	// existing code constructing a plain Node{Tag: ..., InnerText: ...}
+	// keeps working unchanged.
+	Type NodeType
 	// Children holds the child nodes of this Node.
 	Children []*Node
-	// InnerText contains the text within the XML node.
+	// InnerText contains the text within the XML node. For an
+	// ElementNode, it is populated from TextNode/CDATANode children on
+	// decode (see concatenatedText) but may also be set directly on
+	// hand-built nodes that have no such children. For TextNode,
+	// CDATANode, and CommentNode, it holds that node's own content.
 	InnerText string
 	// Attributes holds the attributes of the XML node.
 	Attributes map[string]string
+	// Namespace is the namespace URI this element belongs to, if any.
+	Namespace string
+	// Prefix is the namespace prefix used on the wire for this element,
+	// e.g. "dc" for <dc:creator>. Empty means no prefix (the default
+	// namespace, or no namespace at all).
+	Prefix string
+	// NamespaceDecls holds the xmlns declarations attached to this
+	// element, keyed by prefix ("" for the default xmlns).
+	NamespaceDecls map[string]string
+	// attributeNS holds namespace-qualified attribute values, keyed by
+	// Clark notation ({uri}local), for lookups via GetAttributeNS.
+	attributeNS map[string]string
+	// attributePrefixes maps a namespace-qualified attribute's local name
+	// to the prefix it was declared under, so MarshalXML can re-emit it
+	// as prefix:local instead of losing the prefix.
+	attributePrefixes map[string]string
 }
 
-// UnmarshalXML implements the xml.Unmarshaler interface for Node.
+// UnmarshalXML implements the xml.Unmarshaler interface for Node. It
+// decodes with the zero DecoderOptions, matching the historical behavior
+// of trimming whitespace and dropping comments. Use
+// UnmarshalXMLWithOptions or Decode for mixed-content fidelity.
 func (n *Node) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return n.unmarshalWithContext(d, start, &decodeContext{})
+}
+
+// UnmarshalXMLWithOptions decodes n from d like UnmarshalXML, but honors
+// opts for whitespace, comment, and CDATA preservation. CDATA detection
+// needs to inspect the raw byte stream behind d, which an
+// already-constructed *xml.Decoder cannot expose; CDATA sections decoded
+// through this method are treated as ordinary character data. Use Decode
+// when CDATA fidelity matters.
+func (n *Node) UnmarshalXMLWithOptions(d *xml.Decoder, start xml.StartElement, opts DecoderOptions) error {
+	return n.unmarshalWithContext(d, start, &decodeContext{opts: opts})
+}
+
+// unmarshalWithContext is the shared decode loop behind UnmarshalXML,
+// UnmarshalXMLWithOptions, and Decode.
+func (n *Node) unmarshalWithContext(d *xml.Decoder, start xml.StartElement, ctx *decodeContext) error {
 	n.Tag = start.Name.Local
+	n.Namespace = start.Name.Space
 	n.Attributes = make(map[string]string)
+	var namespacedAttrs []xml.Attr
 	for _, attr := range start.Attr {
-		n.Attributes[attr.Name.Local] = attr.Value
+		switch {
+		case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+			n.setNamespaceDecl("", attr.Value)
+		case attr.Name.Space == "xmlns":
+			n.setNamespaceDecl(attr.Name.Local, attr.Value)
+		case attr.Name.Space != "":
+			n.Attributes[attr.Name.Local] = attr.Value
+			n.setAttributeNS(attr.Name.Space, attr.Name.Local, attr.Value)
+			namespacedAttrs = append(namespacedAttrs, attr)
+		default:
+			n.Attributes[attr.Name.Local] = attr.Value
+		}
+	}
+	n.Prefix = n.lookupPrefix(n.Namespace)
+	for _, attr := range namespacedAttrs {
+		n.setAttributePrefix(attr.Name.Local, n.lookupPrefix(attr.Name.Space))
 	}
 	for {
 		token, err := d.Token()
@@ -39,26 +101,100 @@ func (n *Node) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 		switch elem := token.(type) {
 		case xml.StartElement:
 			child := &Node{Parent: n}
-			err := child.UnmarshalXML(d, elem)
+			err := child.unmarshalWithContext(d, elem, ctx)
 			if err != nil {
 				return ers.Trace(err)
 			}
 			n.Children = append(n.Children, child)
 		case xml.CharData:
-			n.InnerText = strings.TrimSpace(string(elem))
+			n.appendCharData(string(elem), ctx.wasCDATA(d), ctx.opts)
+		case xml.Comment:
+			if ctx.opts.PreserveComments {
+				n.Children = append(n.Children, &Node{Parent: n, Type: CommentNode, InnerText: string(elem)})
+			}
+		case xml.ProcInst:
+			n.Children = append(n.Children, &Node{Parent: n, Type: ProcInstNode, Tag: elem.Target, InnerText: string(elem.Inst)})
 		case xml.EndElement:
 			if elem.Name.Local == n.Tag {
+				n.InnerText = n.concatenatedText()
 				return nil
 			}
 		}
 	}
 }
 
-// MarshalXML implements the xml.Marshaler interface for Node.
+// appendCharData turns a CharData token into a TextNode or CDATANode
+// child, honoring opts. Whitespace-only text is dropped unless
+// PreserveWhitespace is set; CDATA sections are kept distinct from
+// ordinary text only when PreserveCDATA is set.
+func (n *Node) appendCharData(text string, isCDATA bool, opts DecoderOptions) {
+	if isCDATA && opts.PreserveCDATA {
+		n.Children = append(n.Children, &Node{Parent: n, Type: CDATANode, InnerText: text})
+		return
+	}
+	if !opts.PreserveWhitespace {
+		text = strings.TrimSpace(text)
+	}
+	if text == "" {
+		return
+	}
+	n.Children = append(n.Children, &Node{Parent: n, Type: TextNode, InnerText: text})
+}
+
+// concatenatedText returns the concatenation, in document order, of n's
+// TextNode and CDATANode children. It backs the InnerText field so that
+// code reading InnerText continues to see the element's text content
+// regardless of whether preserve options split it across children.
+func (n *Node) concatenatedText() string {
+	var sb strings.Builder
+	for _, child := range n.Children {
+		if child.Type == TextNode || child.Type == CDATANode {
+			sb.WriteString(child.InnerText)
+		}
+	}
+	return sb.String()
+}
+
+// MarshalXML implements the xml.Marshaler interface for Node. Since this is
+// always the top-level node of the Encode call, it also hoists namespace
+// declarations its subtree relies on but doesn't itself carry, so that
+// marshaling a subtree detached from its original document (e.g. a node
+// found via FindChildNS) still produces well-formed, unambiguous XML.
 func (n *Node) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return n.marshalNode(e, start, true)
+}
+
+// marshalNode is the shared encode logic behind MarshalXML and
+// encodeAsChild. hoistDecls is true only for the node xml.Encoder dispatches
+// to directly (the top of the call); descendants reached through
+// encodeAsChild already have their decls covered by an ancestor's own
+// NamespaceDecls or by that hoisting, so they pass hoistDecls=false.
+func (n *Node) marshalNode(e *xml.Encoder, start xml.StartElement, hoistDecls bool) error {
 	start.Name.Local = n.Tag
+	if n.Prefix != "" {
+		start.Name.Local = n.Prefix + ":" + n.Tag
+	}
+	decls := n.NamespaceDecls
+	if hoistDecls {
+		decls = make(map[string]string, len(n.NamespaceDecls))
+		for prefix, uri := range n.NamespaceDecls {
+			decls[prefix] = uri
+		}
+		n.collectUnboundDecls(nil, decls)
+	}
+	for prefix, uri := range decls {
+		attrName := "xmlns"
+		if prefix != "" {
+			attrName = "xmlns:" + prefix
+		}
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: attrName}, Value: uri})
+	}
 	for key, value := range n.Attributes {
-		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: key}, Value: value})
+		attrName := key
+		if prefix := n.attributePrefixes[key]; prefix != "" {
+			attrName = prefix + ":" + key
+		}
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: attrName}, Value: value})
 	}
 	if len(n.Children) == 0 && n.InnerText == "" {
 		err := e.EncodeToken(start)
@@ -71,15 +207,14 @@ func (n *Node) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 	if err != nil {
 		return ers.Trace(err)
 	}
-	if n.InnerText != "" {
+	if n.InnerText != "" && !n.hasTextChildren() {
 		err = e.EncodeToken(xml.CharData([]byte(n.InnerText)))
 		if err != nil {
 			return ers.Trace(err)
 		}
 	}
 	for _, child := range n.Children {
-		err = e.Encode(child)
-		if err != nil {
+		if err := child.encodeAsChild(e); err != nil {
 			return ers.Trace(err)
 		}
 	}
@@ -90,6 +225,84 @@ func (n *Node) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 	return e.Flush()
 }
 
+// hasTextChildren reports whether n has any TextNode or CDATANode child,
+// meaning n.InnerText is already represented by those children and must
+// not also be emitted as a separate, duplicate CharData token.
+func (n *Node) hasTextChildren() bool {
+	for _, child := range n.Children {
+		if child.Type == TextNode || child.Type == CDATANode {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeAsChild writes n as a child token of its parent, dispatching on
+// Type since only ElementNode children go through the regular
+// MarshalXML/e.Encode path.
+func (n *Node) encodeAsChild(e *xml.Encoder) error {
+	switch n.Type {
+	case TextNode:
+		if err := e.EncodeToken(xml.CharData([]byte(n.InnerText))); err != nil {
+			return ers.Trace(err)
+		}
+	case CDATANode:
+		if err := encodeCDATA(e, n.InnerText); err != nil {
+			return ers.Trace(err)
+		}
+	case CommentNode:
+		if err := e.EncodeToken(xml.Comment([]byte(n.InnerText))); err != nil {
+			return ers.Trace(err)
+		}
+	case ProcInstNode:
+		if err := e.EncodeToken(xml.ProcInst{Target: n.Tag, Inst: []byte(n.InnerText)}); err != nil {
+			return ers.Trace(err)
+		}
+	default:
+		if err := n.marshalNode(e, xml.StartElement{}, false); err != nil {
+			return ers.Trace(err)
+		}
+	}
+	return nil
+}
+
+// collectUnboundDecls walks n's subtree looking for prefixes n or its
+// descendants use (on an element name or a namespaced attribute) that
+// aren't declared by scope or by a closer descendant's own NamespaceDecls,
+// adding any it finds to out so a detached subtree can still hoist them.
+func (n *Node) collectUnboundDecls(scope map[string]string, out map[string]string) {
+	local := scope
+	if len(n.NamespaceDecls) > 0 {
+		local = make(map[string]string, len(scope)+len(n.NamespaceDecls))
+		for prefix, uri := range scope {
+			local[prefix] = uri
+		}
+		for prefix, uri := range n.NamespaceDecls {
+			local[prefix] = uri
+		}
+	}
+	addUnboundDecl(n.Prefix, n.Namespace, local, out)
+	for key := range n.attributeNS {
+		attrURI, attrLocal := parsePathSegment(key)
+		addUnboundDecl(n.attributePrefixes[attrLocal], attrURI, local, out)
+	}
+	for _, child := range n.Children {
+		child.collectUnboundDecls(local, out)
+	}
+}
+
+// addUnboundDecl records prefix/uri in out unless scope already binds
+// prefix to that same uri.
+func addUnboundDecl(prefix, uri string, scope, out map[string]string) {
+	if prefix == "" || uri == "" {
+		return
+	}
+	if declURI, ok := scope[prefix]; ok && declURI == uri {
+		return
+	}
+	out[prefix] = uri
+}
+
 // FindChild recursively searches for the first occurrence of a node with the given tag name within the XML node tree.
 func (n *Node) FindChild(tag string) *Node {
 	if n == nil {
@@ -106,6 +319,23 @@ func (n *Node) FindChild(tag string) *Node {
 	return nil
 }
 
+// FindChildNS recursively searches for the first occurrence of a node with
+// the given namespace URI and local tag name within the XML node tree.
+func (n *Node) FindChildNS(uri, local string) *Node {
+	if n == nil {
+		return nil
+	}
+	if n.Tag == local && n.Namespace == uri {
+		return n
+	}
+	for _, child := range n.Children {
+		if found := child.FindChildNS(uri, local); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
 // AppendChild appends a child node to the Node and returns the child node.
 func (n *Node) AppendChild(node *Node) *Node {
 	node.Parent = n
@@ -152,6 +382,15 @@ func (n *Node) HasAttribute(name string) bool {
 	return has
 }
 
+// GetAttributeNS gets the namespace-qualified attribute identified by uri
+// and local from the XML node.
+func (n *Node) GetAttributeNS(uri, local string) string {
+	if n.attributeNS == nil {
+		return ""
+	}
+	return n.attributeNS[nsKey(uri, local)]
+}
+
 // RemoveAttribute removes the given attribute from the XML node.
 func (n *Node) RemoveAttribute(name string) {
 	if n.Attributes == nil {
@@ -160,18 +399,21 @@ func (n *Node) RemoveAttribute(name string) {
 	delete(n.Attributes, name)
 }
 
-// RemoveChildrenWithTag removes all children with the specified tag.
+// RemoveChildrenWithTag removes all children with the specified tag. tag
+// may be in Clark notation ({uri}local) to match on namespace as well.
 func (n *Node) RemoveChildrenWithTag(tag string) {
 	var newChildren []*Node
 	for _, child := range n.Children {
-		if child.Tag != tag {
+		if !matchesPathSegment(child, tag) {
 			newChildren = append(newChildren, child)
 		}
 	}
 	n.Children = newChildren
 }
 
-// CheckPath checks if a specified path exists from the current node using DFS.
+// CheckPath checks if a specified path exists from the current node using
+// DFS. Each segment may be in Clark notation ({uri}local) to match on
+// namespace as well as tag name.
 func (n *Node) CheckPath(path ...string) bool {
 	if len(path) == 0 {
 		return true
@@ -185,7 +427,7 @@ func (n *Node) dfsCheckPath(path []string) bool {
 		return true
 	}
 	for _, child := range n.Children {
-		if child.Tag == path[0] {
+		if matchesPathSegment(child, path[0]) {
 			if child.dfsCheckPath(path[1:]) {
 				return true
 			}
@@ -194,11 +436,13 @@ func (n *Node) dfsCheckPath(path []string) bool {
 	return false
 }
 
-// CreatePath creates a path of nested XML nodes based on the provided tags.
+// CreatePath creates a path of nested XML nodes based on the provided
+// tags. Each segment may be in Clark notation ({uri}local) to tag the
+// created node with a namespace.
 func (n *Node) CreatePath(path ...string) *Node {
 	currentNode := n
-	for _, tag := range path {
-		currentNode = currentNode.AppendChild(&Node{Tag: tag})
+	for _, seg := range path {
+		currentNode = currentNode.AppendChild(newNodeFromPathSegment(seg))
 	}
 	return currentNode
 }
@@ -212,13 +456,15 @@ func (n *Node) CreateUniquePath(path ...string) *Node {
 	return n.CreatePath(path...)
 }
 
-// EnsurePath checks if a specified path exists from the current node and creates any missing nodes.
+// EnsurePath checks if a specified path exists from the current node and
+// creates any missing nodes. Each segment may be in Clark notation
+// ({uri}local) to match and create nodes by namespace as well as tag name.
 func (n *Node) EnsurePath(path ...string) *Node {
 	currentNode := n
-	for i, tag := range path {
+	for i, seg := range path {
 		found := false
 		for _, child := range currentNode.Children {
-			if child.Tag == tag {
+			if matchesPathSegment(child, seg) {
 				currentNode = child
 				found = true
 				break
@@ -244,3 +490,83 @@ func (n *Node) Bytes() []byte {
 func (n *Node) String() string {
 	return string(n.Bytes())
 }
+
+// setNamespaceDecl records an xmlns declaration on n, keyed by prefix
+// ("" for the default xmlns).
+func (n *Node) setNamespaceDecl(prefix, uri string) {
+	if n.NamespaceDecls == nil {
+		n.NamespaceDecls = make(map[string]string)
+	}
+	n.NamespaceDecls[prefix] = uri
+}
+
+// setAttributeNS records the namespace-qualified value of an attribute for
+// later lookup via GetAttributeNS.
+func (n *Node) setAttributeNS(uri, local, value string) {
+	if n.attributeNS == nil {
+		n.attributeNS = make(map[string]string)
+	}
+	n.attributeNS[nsKey(uri, local)] = value
+}
+
+// setAttributePrefix records the prefix a namespace-qualified attribute was
+// declared under, so MarshalXML can re-emit it as prefix:local.
+func (n *Node) setAttributePrefix(local, prefix string) {
+	if n.attributePrefixes == nil {
+		n.attributePrefixes = make(map[string]string)
+	}
+	n.attributePrefixes[local] = prefix
+}
+
+// lookupPrefix walks n and its ancestors for a namespace declaration
+// matching uri, returning the prefix it was declared under ("" for the
+// default xmlns, or if no declaration is found).
+func (n *Node) lookupPrefix(uri string) string {
+	if uri == "" {
+		return ""
+	}
+	for cur := n; cur != nil; cur = cur.Parent {
+		for prefix, declURI := range cur.NamespaceDecls {
+			if declURI == uri {
+				return prefix
+			}
+		}
+	}
+	return ""
+}
+
+// nsKey builds the Clark notation key ({uri}local) used to index
+// namespace-qualified attribute values.
+func nsKey(uri, local string) string {
+	return "{" + uri + "}" + local
+}
+
+// parsePathSegment splits a path segment in Clark notation ({uri}local)
+// into its namespace URI and local name. A segment without a leading
+// "{uri}" is returned as-is with an empty namespace.
+func parsePathSegment(seg string) (namespace, local string) {
+	if strings.HasPrefix(seg, "{") {
+		if end := strings.Index(seg, "}"); end != -1 {
+			return seg[1:end], seg[end+1:]
+		}
+	}
+	return "", seg
+}
+
+// matchesPathSegment reports whether n matches a path segment, comparing
+// by namespace and tag when the segment is in Clark notation, or by tag
+// alone otherwise.
+func matchesPathSegment(n *Node, seg string) bool {
+	namespace, local := parsePathSegment(seg)
+	if namespace != "" {
+		return n.Tag == local && n.Namespace == namespace
+	}
+	return n.Tag == local
+}
+
+// newNodeFromPathSegment creates a Node for a path segment, tagging it
+// with a namespace when the segment is in Clark notation.
+func newNodeFromPathSegment(seg string) *Node {
+	namespace, local := parsePathSegment(seg)
+	return &Node{Tag: local, Namespace: namespace}
+}
@@ -0,0 +1,112 @@
+package gml
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+var xmlDataWithNamespaces = `
+    <feed xmlns="http://www.w3.org/2005/Atom" xmlns:dc="http://purl.org/dc/elements/1.1/">
+        <entry>
+            <title>Hello World</title>
+            <dc:creator>Jane Doe</dc:creator>
+        </entry>
+    </feed>`
+
+// TestUnmarshalXMLNamespaces tests that namespace URIs, prefixes, and
+// declarations survive UnmarshalXML.
+func TestUnmarshalXMLNamespaces(t *testing.T) {
+	var root Node
+	if err := xml.Unmarshal([]byte(xmlDataWithNamespaces), &root); err != nil {
+		t.Fatalf("Error during XML unmarshaling: %v", err)
+	}
+
+	if root.Namespace != "http://www.w3.org/2005/Atom" {
+		t.Errorf("Expected feed namespace to be the Atom URI, got '%s'", root.Namespace)
+	}
+	if root.NamespaceDecls[""] != "http://www.w3.org/2005/Atom" {
+		t.Errorf("Expected default xmlns declaration to be recorded, got %v", root.NamespaceDecls)
+	}
+	if root.NamespaceDecls["dc"] != "http://purl.org/dc/elements/1.1/" {
+		t.Errorf("Expected dc xmlns declaration to be recorded, got %v", root.NamespaceDecls)
+	}
+
+	entry := root.FindChildNS("http://www.w3.org/2005/Atom", "entry")
+	if entry == nil {
+		t.Fatalf("Expected to find entry node by namespace")
+	}
+
+	creator := entry.FindChildNS("http://purl.org/dc/elements/1.1/", "creator")
+	if creator == nil {
+		t.Fatalf("Expected to find dc:creator node by namespace")
+	}
+	if creator.Prefix != "dc" {
+		t.Errorf("Expected dc:creator prefix to be 'dc', got '%s'", creator.Prefix)
+	}
+	if creator.InnerText != "Jane Doe" {
+		t.Errorf("Expected dc:creator text to be 'Jane Doe', got '%s'", creator.InnerText)
+	}
+}
+
+// TestMarshalXMLNamespaces tests that prefixes and xmlns declarations are
+// re-emitted on marshal.
+func TestMarshalXMLNamespaces(t *testing.T) {
+	root := &Node{
+		Tag:       "feed",
+		Namespace: "http://www.w3.org/2005/Atom",
+		NamespaceDecls: map[string]string{
+			"dc": "http://purl.org/dc/elements/1.1/",
+		},
+		Children: []*Node{
+			{
+				Tag:       "creator",
+				Namespace: "http://purl.org/dc/elements/1.1/",
+				Prefix:    "dc",
+				InnerText: "Jane Doe",
+			},
+		},
+	}
+
+	output := root.String()
+	if !strings.Contains(output, `xmlns:dc="http://purl.org/dc/elements/1.1/"`) ||
+		!strings.Contains(output, "<dc:creator>Jane Doe</dc:creator>") {
+		t.Fatalf("Expected marshaled output to preserve xmlns and prefix, got:\n%s", output)
+	}
+}
+
+// TestMarshalXMLNamespacedAttributeDoesNotCorruptDecl tests that a
+// namespaced attribute deeper in the tree doesn't overwrite an ancestor's
+// xmlns declaration with the attribute's value when collectUnboundDecls
+// hoists decls for the top-level marshal.
+func TestMarshalXMLNamespacedAttributeDoesNotCorruptDecl(t *testing.T) {
+	root, err := Decode(strings.NewReader(`<root xmlns:dc="ns"><item dc:creator="me"></item></root>`), DecoderOptions{})
+	if err != nil {
+		t.Fatalf("Error decoding: %v", err)
+	}
+
+	output := root.String()
+	if !strings.Contains(output, `xmlns:dc="ns"`) {
+		t.Fatalf("Expected xmlns:dc to remain 'ns', got:\n%s", output)
+	}
+	if strings.Contains(output, `xmlns:dc="me"`) {
+		t.Fatalf("xmlns:dc was corrupted with the attribute's value, got:\n%s", output)
+	}
+}
+
+// TestEnsurePathNamespace tests that EnsurePath/CheckPath accept Clark
+// notation path segments.
+func TestEnsurePathNamespace(t *testing.T) {
+	root := &Node{Tag: "root"}
+	node := root.EnsurePath("{http://example.com/ns}child")
+
+	if node.Tag != "child" || node.Namespace != "http://example.com/ns" {
+		t.Fatalf("Expected namespaced child node, got tag='%s' ns='%s'", node.Tag, node.Namespace)
+	}
+	if !root.CheckPath("{http://example.com/ns}child") {
+		t.Errorf("Expected CheckPath to find the namespaced child")
+	}
+	if root.CheckPath("{http://other.com/ns}child") {
+		t.Errorf("Expected CheckPath to reject a mismatched namespace")
+	}
+}
@@ -0,0 +1,158 @@
+package gml
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+
+	"github.com/ovila98/ers"
+)
+
+// Sentinel errors a Walk handler can return to control traversal instead
+// of aborting it with a real error.
+var (
+	// SkipSubtree tells Walk not to dispatch nested handler callbacks for
+	// the children of the node the handler just received.
+	SkipSubtree = errors.New("gml: skip subtree")
+	// Stop tells Walk to end the walk immediately without error.
+	Stop = errors.New("gml: stop walk")
+)
+
+// PathSegment is one entry in a StreamDecoder's ancestor stack: an
+// element's tag and attributes, without its children.
+type PathSegment struct {
+	Tag        string
+	Attributes map[string]string
+}
+
+// StreamDecoder reads an XML document one token at a time, materializing
+// only the elements a caller asks for instead of holding the whole
+// document tree in memory.
+type StreamDecoder struct {
+	dec   *xml.Decoder
+	stack []PathSegment
+}
+
+// NewStreamDecoder creates a StreamDecoder reading from r.
+func NewStreamDecoder(r io.Reader) *StreamDecoder {
+	return &StreamDecoder{dec: xml.NewDecoder(r)}
+}
+
+// Path returns the tag and attributes of every ancestor of the element
+// currently being processed, from the document root down to (but not
+// including) that element.
+func (d *StreamDecoder) Path() []PathSegment {
+	path := make([]PathSegment, len(d.stack))
+	copy(path, d.stack)
+	return path
+}
+
+// Next advances through the stream until it finds an element named tag,
+// fully materializes it via Node.UnmarshalXML, and returns it. It returns
+// io.EOF once the document is exhausted without another match.
+func (d *StreamDecoder) Next(tag string) (*Node, error) {
+	for {
+		token, err := d.dec.Token()
+		if err != nil {
+			if err == io.EOF {
+				return nil, io.EOF
+			}
+			return nil, ers.Trace(err)
+		}
+		switch elem := token.(type) {
+		case xml.StartElement:
+			if elem.Name.Local == tag {
+				node := &Node{}
+				if err := node.UnmarshalXML(d.dec, elem); err != nil {
+					return nil, ers.Trace(err)
+				}
+				return node, nil
+			}
+			d.stack = append(d.stack, newPathSegment(elem))
+		case xml.EndElement:
+			d.popStack(elem.Name.Local)
+		}
+	}
+}
+
+// Walk streams through the whole document, invoking the handler
+// registered for an element's tag as soon as that element (and its
+// subtree) has been materialized. By default it then looks for further
+// handler matches nested inside that subtree; a handler can return
+// SkipSubtree to suppress that, or Stop to end the walk immediately.
+func (d *StreamDecoder) Walk(handlers map[string]func(*Node) error) error {
+	for {
+		token, err := d.dec.Token()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return ers.Trace(err)
+		}
+		switch elem := token.(type) {
+		case xml.StartElement:
+			handler, ok := handlers[elem.Name.Local]
+			if !ok {
+				d.stack = append(d.stack, newPathSegment(elem))
+				continue
+			}
+			node := &Node{}
+			if err := node.UnmarshalXML(d.dec, elem); err != nil {
+				return ers.Trace(err)
+			}
+			switch err := handler(node); err {
+			case nil:
+				switch err := d.dispatchNested(node, handlers); err {
+				case nil:
+				case Stop:
+					return nil
+				default:
+					return ers.Trace(err)
+				}
+			case SkipSubtree:
+			case Stop:
+				return nil
+			default:
+				return ers.Trace(err)
+			}
+		case xml.EndElement:
+			d.popStack(elem.Name.Local)
+		}
+	}
+}
+
+// dispatchNested looks for handler matches inside node's already
+// materialized subtree, since Walk's token loop never sees them directly.
+func (d *StreamDecoder) dispatchNested(node *Node, handlers map[string]func(*Node) error) error {
+	d.stack = append(d.stack, PathSegment{Tag: node.Tag, Attributes: node.Attributes})
+	defer func() { d.stack = d.stack[:len(d.stack)-1] }()
+	for _, child := range node.Children {
+		if handler, ok := handlers[child.Tag]; ok {
+			switch err := handler(child); err {
+			case nil:
+			case SkipSubtree:
+				continue
+			default:
+				return err
+			}
+		}
+		if err := d.dispatchNested(child, handlers); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *StreamDecoder) popStack(tag string) {
+	if len(d.stack) > 0 && d.stack[len(d.stack)-1].Tag == tag {
+		d.stack = d.stack[:len(d.stack)-1]
+	}
+}
+
+func newPathSegment(elem xml.StartElement) PathSegment {
+	attrs := make(map[string]string, len(elem.Attr))
+	for _, attr := range elem.Attr {
+		attrs[attr.Name.Local] = attr.Value
+	}
+	return PathSegment{Tag: elem.Name.Local, Attributes: attrs}
+}
@@ -0,0 +1,103 @@
+package gml
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+// TestStreamDecoderNext tests advancing the StreamDecoder to successive
+// elements matching a tag.
+func TestStreamDecoderNext(t *testing.T) {
+	dec := NewStreamDecoder(strings.NewReader(xmlDataToUnmarshal))
+
+	book, err := dec.Next("book")
+	if err != nil {
+		t.Fatalf("Error advancing to first book: %v", err)
+	}
+	if title := book.FindChild("title"); title == nil || title.InnerText != "The Great Gatsby" {
+		t.Fatalf("Expected first book title to be 'The Great Gatsby', got %v", title)
+	}
+
+	book, err = dec.Next("book")
+	if err != nil {
+		t.Fatalf("Error advancing to second book: %v", err)
+	}
+	if title := book.FindChild("title"); title == nil || title.InnerText != "1984" {
+		t.Fatalf("Expected second book title to be '1984', got %v", title)
+	}
+
+	if _, err := dec.Next("nonexistent"); err != io.EOF {
+		t.Fatalf("Expected io.EOF once the document is exhausted, got %v", err)
+	}
+}
+
+// TestStreamDecoderWalk tests SAX-like callbacks for nested tags,
+// including the SkipSubtree sentinel.
+func TestStreamDecoderWalk(t *testing.T) {
+	dec := NewStreamDecoder(strings.NewReader(xmlDataToUnmarshal))
+
+	var titles []string
+	var sections int
+	err := dec.Walk(map[string]func(*Node) error{
+		"section": func(n *Node) error {
+			sections++
+			return nil
+		},
+		"title": func(n *Node) error {
+			titles = append(titles, n.InnerText)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Error walking document: %v", err)
+	}
+	if sections != 2 {
+		t.Errorf("Expected 2 sections, got %d", sections)
+	}
+	if len(titles) != 4 || titles[0] != "The Great Gatsby" {
+		t.Fatalf("Expected 4 titles starting with 'The Great Gatsby', got %v", titles)
+	}
+}
+
+// TestStreamDecoderWalkSkipSubtree tests that SkipSubtree suppresses
+// nested handler dispatch.
+func TestStreamDecoderWalkSkipSubtree(t *testing.T) {
+	dec := NewStreamDecoder(strings.NewReader(xmlDataToUnmarshal))
+
+	var titles int
+	err := dec.Walk(map[string]func(*Node) error{
+		"section": func(n *Node) error {
+			return SkipSubtree
+		},
+		"title": func(n *Node) error {
+			titles++
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Error walking document: %v", err)
+	}
+	if titles != 0 {
+		t.Errorf("Expected SkipSubtree to suppress nested title callbacks, got %d", titles)
+	}
+}
+
+// TestStreamDecoderWalkStop tests that Stop ends the walk immediately.
+func TestStreamDecoderWalkStop(t *testing.T) {
+	dec := NewStreamDecoder(strings.NewReader(xmlDataToUnmarshal))
+
+	var sections int
+	err := dec.Walk(map[string]func(*Node) error{
+		"section": func(n *Node) error {
+			sections++
+			return Stop
+		},
+	})
+	if err != nil {
+		t.Fatalf("Error walking document: %v", err)
+	}
+	if sections != 1 {
+		t.Errorf("Expected Stop to end the walk after the first section, got %d", sections)
+	}
+}
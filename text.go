@@ -0,0 +1,109 @@
+package gml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+
+	"github.com/ovila98/ers"
+)
+
+// NodeType identifies what kind of XML construct a Node represents.
+type NodeType int
+
+const (
+	// ElementNode is a regular tagged element. It is the zero value, so
+	// every Node built before NodeType existed is still an ElementNode.
+	ElementNode NodeType = iota
+	// TextNode is a run of character data.
+	TextNode
+	// CDATANode is a CDATA section, kept distinct from TextNode so it can
+	// be re-emitted verbatim instead of escaped.
+	CDATANode
+	// CommentNode is an XML comment.
+	CommentNode
+	// ProcInstNode is a processing instruction; its Tag holds the target
+	// and its InnerText holds the instruction content.
+	ProcInstNode
+)
+
+// DecoderOptions controls how UnmarshalXMLWithOptions and Decode handle
+// text, CDATA, and comments. The zero value reproduces the historical
+// behavior of UnmarshalXML: whitespace-only text and comments are
+// dropped, and CDATA sections are indistinguishable from ordinary text.
+type DecoderOptions struct {
+	// PreserveWhitespace keeps whitespace-only text nodes instead of
+	// discarding them, and stops trimming the text that is kept.
+	PreserveWhitespace bool
+	// PreserveComments keeps comments as CommentNode children instead of
+	// discarding them.
+	PreserveComments bool
+	// PreserveCDATA keeps CDATA sections as CDATANode children instead of
+	// folding them into ordinary TextNode children. This only takes
+	// effect when decoding through Decode, which owns the raw byte
+	// stream needed to tell CDATA apart from plain text.
+	PreserveCDATA bool
+}
+
+// decodeContext carries the options and (when available) the raw byte
+// buffer needed to detect CDATA sections through a single recursive
+// decode of a Node tree.
+type decodeContext struct {
+	opts DecoderOptions
+	raw  *bytes.Buffer
+}
+
+// wasCDATA reports whether the CharData token d just returned came from a
+// CDATA section, by checking whether the raw bytes consumed so far end in
+// the CDATA closing delimiter. It returns false whenever ctx has no raw
+// buffer, i.e. when decoding through UnmarshalXML/UnmarshalXMLWithOptions
+// directly against a caller-supplied *xml.Decoder.
+func (ctx *decodeContext) wasCDATA(d *xml.Decoder) bool {
+	if ctx.raw == nil {
+		return false
+	}
+	end := int(d.InputOffset())
+	data := ctx.raw.Bytes()
+	if end > len(data) {
+		end = len(data)
+	}
+	return bytes.HasSuffix(data[:end], []byte("]]>"))
+}
+
+// Decode parses an XML document from r into a *Node tree rooted at the
+// first element, honoring opts. Unlike UnmarshalXMLWithOptions, Decode
+// can distinguish CDATA sections from ordinary text, because it owns the
+// raw byte stream behind the decoder.
+func Decode(r io.Reader, opts DecoderOptions) (*Node, error) {
+	var raw bytes.Buffer
+	dec := xml.NewDecoder(io.TeeReader(r, &raw))
+	ctx := &decodeContext{opts: opts, raw: &raw}
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, ers.Trace(err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		root := &Node{}
+		if err := root.unmarshalWithContext(dec, start, ctx); err != nil {
+			return nil, ers.Trace(err)
+		}
+		return root, nil
+	}
+}
+
+// encodeCDATA emits text as a literal <![CDATA[...]]> section.
+// encoding/xml has no CDATA token type, so this reuses xml.Directive,
+// whose content is written unescaped. xml.Directive rejects content with
+// unbalanced '<'/'>', which a CDATA payload can legitimately contain (e.g.
+// "a < b"); when that happens, encodeCDATA falls back to emitting the
+// text as ordinary escaped CharData rather than failing the whole encode.
+func encodeCDATA(e *xml.Encoder, text string) error {
+	if err := e.EncodeToken(xml.Directive("[CDATA[" + text + "]]")); err == nil {
+		return nil
+	}
+	return e.EncodeToken(xml.CharData([]byte(text)))
+}
@@ -0,0 +1,111 @@
+package gml
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestDecodeDefaultOptionsDropsWhitespaceAndComments tests that Decode with
+// the zero DecoderOptions keeps matching the historical UnmarshalXML
+// behavior: only significant text survives, and comments are dropped.
+func TestDecodeDefaultOptionsDropsWhitespaceAndComments(t *testing.T) {
+	root, err := Decode(strings.NewReader(`
+		<doc>
+			<!-- a note -->
+			<p>hello</p>
+		</doc>`), DecoderOptions{})
+	if err != nil {
+		t.Fatalf("Error decoding: %v", err)
+	}
+
+	p := root.FindChild("p")
+	if p == nil || p.InnerText != "hello" {
+		t.Fatalf("Expected <p> text 'hello', got %v", p)
+	}
+
+	var sawComment bool
+	root.Walk(func(c *Node) bool {
+		if c.Type == CommentNode {
+			sawComment = true
+		}
+		return true
+	})
+	if sawComment {
+		t.Fatalf("Expected comments to be dropped by default")
+	}
+}
+
+// TestDecodeMixedContent tests that PreserveWhitespace keeps a mixed
+// content element's full text instead of losing everything after the
+// first nested element.
+func TestDecodeMixedContent(t *testing.T) {
+	root, err := Decode(strings.NewReader(`<p>hello <b>world</b>!</p>`), DecoderOptions{PreserveWhitespace: true})
+	if err != nil {
+		t.Fatalf("Error decoding: %v", err)
+	}
+
+	if len(root.Children) != 3 {
+		t.Fatalf("Expected 3 children (text, <b>, text), got %d", len(root.Children))
+	}
+	if root.Children[0].Type != TextNode || root.Children[0].InnerText != "hello " {
+		t.Errorf("Expected first child to be text 'hello ', got %+v", root.Children[0])
+	}
+	if root.Children[1].Type != ElementNode || root.Children[1].Tag != "b" {
+		t.Errorf("Expected second child to be element <b>, got %+v", root.Children[1])
+	}
+	if root.Children[2].Type != TextNode || root.Children[2].InnerText != "!" {
+		t.Errorf("Expected third child to be text '!', got %+v", root.Children[2])
+	}
+	if root.InnerText != "hello !" {
+		t.Errorf("Expected InnerText to concatenate text children, got %q", root.InnerText)
+	}
+}
+
+// TestDecodePreserveComments tests that PreserveComments keeps comments
+// as CommentNode children.
+func TestDecodePreserveComments(t *testing.T) {
+	root, err := Decode(strings.NewReader(`<doc><!--note--><p>hi</p></doc>`), DecoderOptions{PreserveComments: true})
+	if err != nil {
+		t.Fatalf("Error decoding: %v", err)
+	}
+
+	if len(root.Children) != 2 || root.Children[0].Type != CommentNode || root.Children[0].InnerText != "note" {
+		t.Fatalf("Expected a CommentNode child holding 'note', got %+v", root.Children)
+	}
+}
+
+// TestDecodePreserveCDATA tests that PreserveCDATA keeps a CDATA section
+// distinct from ordinary text, and that MarshalXML re-emits it verbatim.
+func TestDecodePreserveCDATA(t *testing.T) {
+	root, err := Decode(strings.NewReader(`<script><![CDATA[if (1 < 2) alert("hi");]]></script>`), DecoderOptions{PreserveCDATA: true})
+	if err != nil {
+		t.Fatalf("Error decoding: %v", err)
+	}
+
+	if len(root.Children) != 1 || root.Children[0].Type != CDATANode {
+		t.Fatalf("Expected a single CDATANode child, got %+v", root.Children)
+	}
+	if root.Children[0].InnerText != `if (1 < 2) alert("hi");` {
+		t.Errorf("Expected CDATA content preserved verbatim, got %q", root.Children[0].InnerText)
+	}
+}
+
+// TestMarshalXMLCommentAndCDATA tests that MarshalXML emits comment and
+// CDATA children verbatim rather than escaping them as text.
+func TestMarshalXMLCommentAndCDATA(t *testing.T) {
+	root := &Node{
+		Tag: "doc",
+		Children: []*Node{
+			{Type: CommentNode, InnerText: "note"},
+			{Type: CDATANode, InnerText: "<b>raw</b>"},
+		},
+	}
+
+	output := root.String()
+	if !strings.Contains(output, "<!--note-->") {
+		t.Errorf("Expected output to contain the comment verbatim, got:\n%s", output)
+	}
+	if !strings.Contains(output, "<![CDATA[<b>raw</b>]]>") {
+		t.Errorf("Expected output to contain the CDATA section verbatim, got:\n%s", output)
+	}
+}
@@ -0,0 +1,584 @@
+package gml
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ovila98/ers"
+)
+
+// Query is a compiled XPath expression. Compiling once and reusing the
+// Query avoids re-parsing the same expression when it is evaluated against
+// many documents.
+type Query struct {
+	absolute     bool
+	elementSteps []xpathStep
+	valueKind    xpathValueKind
+	valueName    string
+}
+
+// xpathAxis describes how a step selects candidate nodes from its context.
+type xpathAxis int
+
+const (
+	axisChild xpathAxis = iota
+	axisDescendantOrSelf
+	axisSelf
+)
+
+// xpathTestKind distinguishes element steps from the text()/@name node tests
+// that can only ever appear as the final component of an expression.
+type xpathTestKind int
+
+const (
+	testElement xpathTestKind = iota
+	testText
+	testAttr
+)
+
+// xpathValueKind records whether a compiled Query ends in a value-producing
+// node test, and if so which kind.
+type xpathValueKind int
+
+const (
+	valueNone xpathValueKind = iota
+	valueText
+	valueAttr
+)
+
+func (k xpathValueKind) String() string {
+	switch k {
+	case valueText:
+		return "text()"
+	case valueAttr:
+		return "an attribute"
+	default:
+		return "nothing"
+	}
+}
+
+// xpathStep is one slash-separated component of an XPath expression, e.g.
+// `section[@name='Fiction']` or `book[2]`.
+type xpathStep struct {
+	axis       xpathAxis
+	kind       xpathTestKind
+	name       string // tag or attribute name; "*" for the element wildcard
+	predicates []xpathPredicate
+}
+
+// xpathPredicate evaluates a single bracketed predicate against a candidate
+// node and its 1-based position within the node-set produced by its step.
+type xpathPredicate interface {
+	eval(n *Node, pos, size int) (bool, error)
+}
+
+type andPredicate struct{ left, right xpathPredicate }
+
+func (p *andPredicate) eval(n *Node, pos, size int) (bool, error) {
+	ok, err := p.left.eval(n, pos, size)
+	if err != nil {
+		return false, ers.Trace(err)
+	}
+	if !ok {
+		return false, nil
+	}
+	return p.right.eval(n, pos, size)
+}
+
+type orPredicate struct{ left, right xpathPredicate }
+
+func (p *orPredicate) eval(n *Node, pos, size int) (bool, error) {
+	ok, err := p.left.eval(n, pos, size)
+	if err != nil {
+		return false, ers.Trace(err)
+	}
+	if ok {
+		return true, nil
+	}
+	return p.right.eval(n, pos, size)
+}
+
+type attrExistsPredicate struct{ name string }
+
+func (p *attrExistsPredicate) eval(n *Node, pos, size int) (bool, error) {
+	return n.HasAttribute(p.name), nil
+}
+
+type attrEqPredicate struct{ name, value string }
+
+func (p *attrEqPredicate) eval(n *Node, pos, size int) (bool, error) {
+	return n.HasAttribute(p.name) && n.GetAttribute(p.name) == p.value, nil
+}
+
+type positionPredicate struct{ n int }
+
+func (p *positionPredicate) eval(n *Node, pos, size int) (bool, error) {
+	return pos == p.n, nil
+}
+
+type lastPredicate struct{}
+
+func (p *lastPredicate) eval(n *Node, pos, size int) (bool, error) {
+	return pos == size, nil
+}
+
+type positionCmpPredicate struct {
+	op string
+	n  int
+}
+
+func (p *positionCmpPredicate) eval(n *Node, pos, size int) (bool, error) {
+	switch p.op {
+	case "<":
+		return pos < p.n, nil
+	case ">":
+		return pos > p.n, nil
+	case "<=":
+		return pos <= p.n, nil
+	case ">=":
+		return pos >= p.n, nil
+	case "=":
+		return pos == p.n, nil
+	case "!=":
+		return pos != p.n, nil
+	default:
+		return false, fmt.Errorf("gml: unsupported comparison operator %q", p.op)
+	}
+}
+
+// Compile parses expr into a reusable Query. The same Query can be
+// evaluated against any number of Node trees via Query.Find or
+// Query.QueryValue.
+func Compile(expr string) (*Query, error) {
+	trimmed := strings.TrimSpace(expr)
+	if trimmed == "" {
+		return nil, fmt.Errorf("gml: empty xpath expression")
+	}
+	p := &xpathParser{expr: trimmed}
+	q := &Query{}
+	axis := axisChild
+	if p.consume("//") {
+		axis = axisDescendantOrSelf
+	} else if p.consume("/") {
+		q.absolute = true
+		axis = axisSelf
+	}
+	for {
+		step, err := p.parseStep()
+		if err != nil {
+			return nil, ers.Trace(err)
+		}
+		step.axis = axis
+		q.elementSteps = append(q.elementSteps, step)
+		if p.consume("//") {
+			axis = axisDescendantOrSelf
+			continue
+		}
+		if p.consume("/") {
+			axis = axisChild
+			continue
+		}
+		break
+	}
+	p.skipSpace()
+	if p.pos != len(p.expr) {
+		return nil, fmt.Errorf("gml: unexpected input in xpath expression %q at position %d", expr, p.pos)
+	}
+	for _, step := range q.elementSteps[:len(q.elementSteps)-1] {
+		if step.kind != testElement {
+			return nil, fmt.Errorf("gml: text() and @name may only end xpath expression %q", expr)
+		}
+	}
+	if last := q.elementSteps[len(q.elementSteps)-1]; last.kind != testElement {
+		q.elementSteps = q.elementSteps[:len(q.elementSteps)-1]
+		if last.kind == testText {
+			q.valueKind = valueText
+		} else {
+			q.valueKind = valueAttr
+			q.valueName = last.name
+		}
+	}
+	return q, nil
+}
+
+// Find evaluates the compiled query against n, returning every matching
+// element node. It returns an error if the expression selects a value
+// (text() or an attribute) rather than elements; use QueryValue for those.
+func (q *Query) Find(n *Node) ([]*Node, error) {
+	if n == nil {
+		return nil, nil
+	}
+	if q.valueKind != valueNone {
+		return nil, fmt.Errorf("gml: expression selects %s, use QueryValue instead", q.valueKind)
+	}
+	return q.evaluate(n)
+}
+
+// QueryValue evaluates the compiled query against n and returns the
+// text()/attribute value of the first matching node. It returns an error if
+// the expression does not end in a text() or @name node test.
+func (q *Query) QueryValue(n *Node) (string, error) {
+	if q.valueKind == valueNone {
+		return "", fmt.Errorf("gml: expression does not select text() or an attribute")
+	}
+	nodes, err := q.evaluate(n)
+	if err != nil {
+		return "", ers.Trace(err)
+	}
+	if len(nodes) == 0 {
+		return "", fmt.Errorf("gml: no node matched the xpath expression")
+	}
+	if q.valueKind == valueAttr {
+		return nodes[0].GetAttribute(q.valueName), nil
+	}
+	return nodes[0].InnerText, nil
+}
+
+func (q *Query) evaluate(n *Node) ([]*Node, error) {
+	if q.absolute {
+		for n.Parent != nil {
+			n = n.Parent
+		}
+	}
+	context := []*Node{n}
+	for _, step := range q.elementSteps {
+		next, err := applyXPathStep(step, context)
+		if err != nil {
+			return nil, ers.Trace(err)
+		}
+		context = next
+	}
+	return context, nil
+}
+
+func applyXPathStep(step xpathStep, context []*Node) ([]*Node, error) {
+	var result []*Node
+	for _, ctx := range context {
+		var candidates []*Node
+		switch step.axis {
+		case axisSelf:
+			candidates = []*Node{ctx}
+		case axisChild:
+			candidates = ctx.Children
+		case axisDescendantOrSelf:
+			candidates = collectDescendantOrSelf(ctx)
+		}
+		var matched []*Node
+		for _, c := range candidates {
+			if c.Type != ElementNode {
+				continue
+			}
+			if step.name == "*" || c.Tag == step.name {
+				matched = append(matched, c)
+			}
+		}
+		for _, group := range groupByParent(matched) {
+			filtered, err := filterXPathPredicates(group, step.predicates)
+			if err != nil {
+				return nil, ers.Trace(err)
+			}
+			result = append(result, filtered...)
+		}
+	}
+	return result, nil
+}
+
+// groupByParent splits nodes into runs sharing the same immediate Parent,
+// in order of each group's first appearance, so position()/last() predicates
+// on a descendant-or-self step are evaluated per producing parent instead of
+// across the whole flattened match set.
+func groupByParent(nodes []*Node) [][]*Node {
+	var groups [][]*Node
+	index := make(map[*Node]int)
+	for _, n := range nodes {
+		i, ok := index[n.Parent]
+		if !ok {
+			i = len(groups)
+			index[n.Parent] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], n)
+	}
+	return groups
+}
+
+func filterXPathPredicates(nodes []*Node, predicates []xpathPredicate) ([]*Node, error) {
+	if len(predicates) == 0 {
+		return nodes, nil
+	}
+	var filtered []*Node
+	size := len(nodes)
+	for i, n := range nodes {
+		keep := true
+		for _, pred := range predicates {
+			ok, err := pred.eval(n, i+1, size)
+			if err != nil {
+				return nil, ers.Trace(err)
+			}
+			if !ok {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			filtered = append(filtered, n)
+		}
+	}
+	return filtered, nil
+}
+
+func collectDescendantOrSelf(n *Node) []*Node {
+	nodes := []*Node{n}
+	for _, child := range n.Children {
+		nodes = append(nodes, collectDescendantOrSelf(child)...)
+	}
+	return nodes
+}
+
+// Find evaluates an XPath expression against n, returning every matching
+// element node. Supported syntax: absolute (/) and descendant (//) axes,
+// child steps by tag name, the wildcard (*), attribute predicates
+// ([@x], [@x='v']), position predicates ([N], [last()], [position()<N]),
+// boolean operators (and/or) inside predicates, and the text()/@name node
+// tests (via QueryValue). Compile the expression once with Compile
+// when evaluating it against many documents.
+func (n *Node) Find(expr string) ([]*Node, error) {
+	q, err := Compile(expr)
+	if err != nil {
+		return nil, ers.Trace(err)
+	}
+	return q.Find(n)
+}
+
+// FindOne evaluates expr and returns the first matching node, or nil if
+// nothing matched.
+func (n *Node) FindOne(expr string) (*Node, error) {
+	nodes, err := n.Find(expr)
+	if err != nil {
+		return nil, ers.Trace(err)
+	}
+	if len(nodes) == 0 {
+		return nil, nil
+	}
+	return nodes[0], nil
+}
+
+// QueryValue evaluates an XPath expression ending in text() or @name and
+// returns the value of the first matching node.
+func (n *Node) QueryValue(expr string) (string, error) {
+	q, err := Compile(expr)
+	if err != nil {
+		return "", ers.Trace(err)
+	}
+	return q.QueryValue(n)
+}
+
+// xpathParser turns an XPath expression string into a Query via simple
+// recursive-descent parsing.
+type xpathParser struct {
+	expr string
+	pos  int
+}
+
+func (p *xpathParser) skipSpace() {
+	for p.pos < len(p.expr) && p.expr[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *xpathParser) consume(s string) bool {
+	p.skipSpace()
+	if strings.HasPrefix(p.expr[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+func (p *xpathParser) consumeWord(word string) bool {
+	p.skipSpace()
+	rest := p.expr[p.pos:]
+	if !strings.HasPrefix(rest, word) {
+		return false
+	}
+	after := p.pos + len(word)
+	if after < len(p.expr) && isXPathNameByte(p.expr[after]) {
+		return false
+	}
+	p.pos = after
+	return true
+}
+
+func (p *xpathParser) parseStep() (xpathStep, error) {
+	p.skipSpace()
+	step := xpathStep{kind: testElement}
+	switch {
+	case p.consume("@"):
+		name, err := p.parseName()
+		if err != nil {
+			return step, ers.Trace(err)
+		}
+		step.kind = testAttr
+		step.name = name
+	case p.consume("text()"):
+		step.kind = testText
+	case p.consume("*"):
+		step.name = "*"
+	default:
+		name, err := p.parseName()
+		if err != nil {
+			return step, ers.Trace(err)
+		}
+		step.name = name
+	}
+	for p.consume("[") {
+		pred, err := p.parseOrExpr()
+		if err != nil {
+			return step, ers.Trace(err)
+		}
+		if !p.consume("]") {
+			return step, fmt.Errorf("gml: expected ']' in xpath expression at position %d", p.pos)
+		}
+		step.predicates = append(step.predicates, pred)
+	}
+	return step, nil
+}
+
+func (p *xpathParser) parseOrExpr() (xpathPredicate, error) {
+	left, err := p.parseAndExpr()
+	if err != nil {
+		return nil, ers.Trace(err)
+	}
+	for p.consumeWord("or") {
+		right, err := p.parseAndExpr()
+		if err != nil {
+			return nil, ers.Trace(err)
+		}
+		left = &orPredicate{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *xpathParser) parseAndExpr() (xpathPredicate, error) {
+	left, err := p.parsePrimaryExpr()
+	if err != nil {
+		return nil, ers.Trace(err)
+	}
+	for p.consumeWord("and") {
+		right, err := p.parsePrimaryExpr()
+		if err != nil {
+			return nil, ers.Trace(err)
+		}
+		left = &andPredicate{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *xpathParser) parsePrimaryExpr() (xpathPredicate, error) {
+	p.skipSpace()
+	switch {
+	case p.consume("("):
+		inner, err := p.parseOrExpr()
+		if err != nil {
+			return nil, ers.Trace(err)
+		}
+		if !p.consume(")") {
+			return nil, fmt.Errorf("gml: expected ')' in xpath predicate at position %d", p.pos)
+		}
+		return inner, nil
+	case p.consume("@"):
+		name, err := p.parseName()
+		if err != nil {
+			return nil, ers.Trace(err)
+		}
+		p.skipSpace()
+		if p.consume("=") {
+			value, err := p.parseLiteral()
+			if err != nil {
+				return nil, ers.Trace(err)
+			}
+			return &attrEqPredicate{name: name, value: value}, nil
+		}
+		return &attrExistsPredicate{name: name}, nil
+	case p.consume("last()"):
+		return &lastPredicate{}, nil
+	case p.consume("position()"):
+		op, err := p.parseCmpOp()
+		if err != nil {
+			return nil, ers.Trace(err)
+		}
+		n, err := p.parseNumber()
+		if err != nil {
+			return nil, ers.Trace(err)
+		}
+		return &positionCmpPredicate{op: op, n: n}, nil
+	default:
+		n, err := p.parseNumber()
+		if err != nil {
+			return nil, fmt.Errorf("gml: unexpected token in xpath predicate at position %d", p.pos)
+		}
+		return &positionPredicate{n: n}, nil
+	}
+}
+
+func (p *xpathParser) parseName() (string, error) {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.expr) && isXPathNameByte(p.expr[p.pos]) {
+		p.pos++
+	}
+	if p.pos == start {
+		return "", fmt.Errorf("gml: expected name in xpath expression at position %d", p.pos)
+	}
+	return p.expr[start:p.pos], nil
+}
+
+func (p *xpathParser) parseLiteral() (string, error) {
+	p.skipSpace()
+	if p.pos >= len(p.expr) {
+		return "", fmt.Errorf("gml: expected quoted string in xpath expression at position %d", p.pos)
+	}
+	quote := p.expr[p.pos]
+	if quote != '\'' && quote != '"' {
+		return "", fmt.Errorf("gml: expected quoted string in xpath expression at position %d", p.pos)
+	}
+	p.pos++
+	start := p.pos
+	for p.pos < len(p.expr) && p.expr[p.pos] != quote {
+		p.pos++
+	}
+	if p.pos >= len(p.expr) {
+		return "", fmt.Errorf("gml: unterminated string literal in xpath expression")
+	}
+	value := p.expr[start:p.pos]
+	p.pos++
+	return value, nil
+}
+
+func (p *xpathParser) parseNumber() (int, error) {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.expr) && p.expr[p.pos] >= '0' && p.expr[p.pos] <= '9' {
+		p.pos++
+	}
+	if p.pos == start {
+		return 0, fmt.Errorf("gml: expected number in xpath expression at position %d", p.pos)
+	}
+	return strconv.Atoi(p.expr[start:p.pos])
+}
+
+func (p *xpathParser) parseCmpOp() (string, error) {
+	p.skipSpace()
+	for _, op := range []string{"<=", ">=", "!=", "<", ">", "="} {
+		if p.consume(op) {
+			return op, nil
+		}
+	}
+	return "", fmt.Errorf("gml: expected comparison operator in xpath expression at position %d", p.pos)
+}
+
+func isXPathNameByte(b byte) bool {
+	return b == '_' || b == '-' || b == '.' || b == ':' ||
+		(b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9')
+}
@@ -0,0 +1,176 @@
+package gml
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+// TestFind tests the Node.Find method against child, descendant, wildcard,
+// and predicate axes.
+func TestFind(t *testing.T) {
+	var root Node
+	if err := xml.Unmarshal([]byte(xmlDataToUnmarshal), &root); err != nil {
+		t.Fatalf("Error during XML unmarshaling: %v", err)
+	}
+
+	titles, err := root.Find("//title")
+	if err != nil {
+		t.Fatalf("Error evaluating //title: %v", err)
+	}
+	if len(titles) != 4 {
+		t.Fatalf("Expected 4 titles, got %d", len(titles))
+	}
+
+	sections, err := root.Find("/library/section[@name='Non-Fiction']")
+	if err != nil {
+		t.Fatalf("Error evaluating absolute path: %v", err)
+	}
+	if len(sections) != 1 || sections[0].GetAttribute("name") != "Non-Fiction" {
+		t.Fatalf("Expected a single Non-Fiction section, got %v", sections)
+	}
+
+	lastBook, err := sections[0].Find("book[last()]")
+	if err != nil {
+		t.Fatalf("Error evaluating book[last()]: %v", err)
+	}
+	if len(lastBook) != 1 || lastBook[0].FindChild("title").InnerText != "Educated" {
+		t.Fatalf("Expected last book to be 'Educated', got %v", lastBook)
+	}
+
+	wildcard, err := root.Find("/library/*")
+	if err != nil {
+		t.Fatalf("Error evaluating wildcard path: %v", err)
+	}
+	if len(wildcard) != 2 {
+		t.Fatalf("Expected 2 child sections under library, got %d", len(wildcard))
+	}
+}
+
+// TestFindDescendantPositionPerParent tests that a position predicate after
+// // is evaluated per producing parent, not across the whole flattened
+// descendant-or-self match set.
+func TestFindDescendantPositionPerParent(t *testing.T) {
+	var root Node
+	if err := xml.Unmarshal([]byte(xmlDataToUnmarshal), &root); err != nil {
+		t.Fatalf("Error during XML unmarshaling: %v", err)
+	}
+
+	firstBooks, err := root.Find("//book[1]")
+	if err != nil {
+		t.Fatalf("Error evaluating //book[1]: %v", err)
+	}
+	if len(firstBooks) != 2 {
+		t.Fatalf("Expected the first book under each of the 2 sections, got %d: %v", len(firstBooks), firstBooks)
+	}
+	if firstBooks[0].FindChild("title").InnerText != "The Great Gatsby" ||
+		firstBooks[1].FindChild("title").InnerText != "Sapiens: A Brief History of Humankind" {
+		t.Fatalf("Expected the first book of each section, got %v", firstBooks)
+	}
+}
+
+// TestFindAbsolutePathFromNonRoot tests that an absolute path evaluates
+// against the true document root, not the node Find is called on.
+func TestFindAbsolutePathFromNonRoot(t *testing.T) {
+	var root Node
+	if err := xml.Unmarshal([]byte(xmlDataToUnmarshal), &root); err != nil {
+		t.Fatalf("Error during XML unmarshaling: %v", err)
+	}
+
+	fiction := root.FindChild("section")
+	sections, err := fiction.Find("/library/section[@name='Non-Fiction']")
+	if err != nil {
+		t.Fatalf("Error evaluating absolute path from a non-root node: %v", err)
+	}
+	if len(sections) != 1 || sections[0].GetAttribute("name") != "Non-Fiction" {
+		t.Fatalf("Expected absolute path called from 'Fiction' section to still resolve 'Non-Fiction', got %v", sections)
+	}
+}
+
+// TestFindWildcardExcludesPseudoNodes tests that the element wildcard (*)
+// matches only ElementNode children, not preserved comment/text/proc-inst
+// pseudo-nodes.
+func TestFindWildcardExcludesPseudoNodes(t *testing.T) {
+	root, err := Decode(strings.NewReader(`<doc><!--note--><p>hi</p></doc>`), DecoderOptions{PreserveComments: true})
+	if err != nil {
+		t.Fatalf("Error decoding: %v", err)
+	}
+
+	nodes, err := root.Find("/doc/*")
+	if err != nil {
+		t.Fatalf("Error evaluating wildcard path: %v", err)
+	}
+	if len(nodes) != 1 || nodes[0].Tag != "p" {
+		t.Fatalf("Expected wildcard to match only the <p> element, got %v", nodes)
+	}
+}
+
+// TestFindOne tests the Node.FindOne method.
+func TestFindOne(t *testing.T) {
+	var root Node
+	if err := xml.Unmarshal([]byte(xmlDataToUnmarshal), &root); err != nil {
+		t.Fatalf("Error during XML unmarshaling: %v", err)
+	}
+
+	book, err := root.FindOne("//section[@name='Fiction']/book[1]")
+	if err != nil {
+		t.Fatalf("Error evaluating FindOne: %v", err)
+	}
+	if book == nil || book.FindChild("title").InnerText != "The Great Gatsby" {
+		t.Fatalf("Expected first Fiction book to be 'The Great Gatsby', got %v", book)
+	}
+
+	missing, err := root.FindOne("//section[@name='Missing']")
+	if err != nil {
+		t.Fatalf("Error evaluating FindOne for missing node: %v", err)
+	}
+	if missing != nil {
+		t.Fatalf("Expected nil for a non-matching expression, got %v", missing)
+	}
+}
+
+// TestQueryValue tests the Node.QueryValue method for text() and @name
+// node tests.
+func TestQueryValue(t *testing.T) {
+	var root Node
+	if err := xml.Unmarshal([]byte(xmlDataToUnmarshal), &root); err != nil {
+		t.Fatalf("Error during XML unmarshaling: %v", err)
+	}
+
+	name, err := root.QueryValue("@name")
+	if err != nil {
+		t.Fatalf("Error evaluating @name: %v", err)
+	}
+	if name != "City Library" {
+		t.Errorf("Expected @name to be 'City Library', got '%s'", name)
+	}
+
+	text, err := root.QueryValue("//section[1]/book[2]/title/text()")
+	if err != nil {
+		t.Fatalf("Error evaluating text(): %v", err)
+	}
+	if text != "1984" {
+		t.Errorf("Expected title text to be '1984', got '%s'", text)
+	}
+}
+
+// TestCompile tests that a compiled Query can be reused across documents.
+func TestCompile(t *testing.T) {
+	query, err := Compile("/library/section/book[position()<2]/title")
+	if err != nil {
+		t.Fatalf("Error compiling xpath expression: %v", err)
+	}
+
+	var root Node
+	if err := xml.Unmarshal([]byte(xmlDataToUnmarshal), &root); err != nil {
+		t.Fatalf("Error during XML unmarshaling: %v", err)
+	}
+
+	titles, err := query.Find(&root)
+	if err != nil {
+		t.Fatalf("Error evaluating compiled query: %v", err)
+	}
+	if len(titles) != 2 || titles[0].InnerText != "The Great Gatsby" || titles[1].InnerText != "Sapiens: A Brief History of Humankind" {
+		t.Fatalf("Expected first book title from each section, got %v", titles)
+	}
+}